@@ -0,0 +1,371 @@
+// Package migrate is a golang-migrate-style schema migration runner
+// built on top of daas.PGClient. Applied versions are tracked in a
+// schema_migrations table and pg_advisory_lock/pg_advisory_unlock guard
+// against two deployments migrating the same database concurrently.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/touno-io/goasa"
+)
+
+// defaultTable is the name of the table used to record applied
+// migrations when MigrateOptions.Table is left empty.
+const defaultTable = "schema_migrations"
+
+// defaultLockID is the pg_advisory_lock key used when
+// MigrateOptions.LockID is left at zero.
+const defaultLockID = 7283990021
+
+// MigrateOptions configures a migration run.
+type MigrateOptions struct {
+	// Table names the table used to record applied versions. Defaults
+	// to "schema_migrations".
+	Table string
+	// LockID is the pg_advisory_lock key held for the duration of a
+	// migration run, so concurrent deployments don't race. Defaults to
+	// a fixed, package-specific key.
+	LockID int64
+}
+
+func (opts MigrateOptions) withDefaults() MigrateOptions {
+	if opts.Table == "" {
+		opts.Table = defaultTable
+	}
+	if opts.LockID == 0 {
+		opts.LockID = defaultLockID
+	}
+	return opts
+}
+
+// MigrationState describes one row of the schema_migrations table.
+type MigrationState struct {
+	Version   int64
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+type migration struct {
+	Version int64
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+// Migrator runs migrations loaded from an fs.FS against a PGClient.
+type Migrator struct {
+	pg     *daas.PGClient
+	source fs.FS
+	opts   MigrateOptions
+}
+
+// New returns a Migrator bound to pg. The source and options passed to
+// Migrate are remembered so MigrateTo, MigrateStatus and Force can be
+// called afterwards without repeating them.
+func New(pg *daas.PGClient) *Migrator {
+	return &Migrator{pg: pg, opts: MigrateOptions{}.withDefaults()}
+}
+
+// Migrate loads every *.up.sql/*.down.sql pair from source and applies
+// every up migration newer than the current schema version, in order.
+// Each migration runs inside its own transaction; a failure leaves its
+// version marked dirty and blocks further migrations until Force is
+// called.
+func (m *Migrator) Migrate(source fs.FS, opts MigrateOptions) error {
+	opts = opts.withDefaults()
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		return fmt.Errorf("Migrate::loadMigrations: %s", err)
+	}
+	m.source, m.opts = source, opts
+
+	return m.withLock(opts, func() error {
+		if err := m.ensureSchema(opts.Table); err != nil {
+			return err
+		}
+		current, dirty, err := m.currentVersion(opts.Table)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("Migrate:: schema_migrations is dirty at version %d, call Force first", current)
+		}
+		for _, mig := range migrations {
+			if mig.Version <= current {
+				continue
+			}
+			if err := m.apply(opts.Table, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateTo migrates forward or backward to the given version, using
+// the source and options passed to the most recent Migrate call.
+func (m *Migrator) MigrateTo(version int64) error {
+	if m.source == nil {
+		return fmt.Errorf("MigrateTo:: called before Migrate established a migration source")
+	}
+	migrations, err := loadMigrations(m.source)
+	if err != nil {
+		return fmt.Errorf("MigrateTo::loadMigrations: %s", err)
+	}
+
+	return m.withLock(m.opts, func() error {
+		if err := m.ensureSchema(m.opts.Table); err != nil {
+			return err
+		}
+		current, dirty, err := m.currentVersion(m.opts.Table)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("MigrateTo:: schema_migrations is dirty at version %d, call Force first", current)
+		}
+
+		if version >= current {
+			for _, mig := range migrations {
+				if mig.Version <= current || mig.Version > version {
+					continue
+				}
+				if err := m.apply(m.opts.Table, mig); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			mig := migrations[i]
+			if mig.Version > current || mig.Version <= version {
+				continue
+			}
+			if err := m.revert(m.opts.Table, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateStatus returns every recorded migration version, oldest first.
+func (m *Migrator) MigrateStatus() ([]MigrationState, error) {
+	table := m.opts.withDefaults().Table
+
+	stx, err := m.pg.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("MigrateStatus::Begin: %s", err)
+	}
+	defer stx.Rollback()
+
+	if err := m.ensureSchema(table); err != nil {
+		return nil, err
+	}
+
+	rows, err := stx.Query(fmt.Sprintf("SELECT version, dirty, applied_at FROM %s ORDER BY version ASC", table))
+	if err != nil {
+		return nil, fmt.Errorf("MigrateStatus::Query: %s", err)
+	}
+	defer rows.Close()
+
+	record, err := stx.FetchAll(rows)
+	if err != nil {
+		return nil, fmt.Errorf("MigrateStatus::FetchAll: %s", err)
+	}
+
+	states := make([]MigrationState, 0, len(record))
+	for _, row := range record {
+		states = append(states, MigrationState{
+			Version:   row.ToInt64("version"),
+			Dirty:     row.ToBoolean("dirty"),
+			AppliedAt: row.ToTime("applied_at"),
+		})
+	}
+	return states, nil
+}
+
+// Force clears the dirty flag at version, unblocking further
+// migrations after a failure has been fixed up manually.
+func (m *Migrator) Force(version int64) error {
+	return m.setVersion(m.opts.withDefaults().Table, version, false)
+}
+
+func (m *Migrator) withLock(opts MigrateOptions, fn func() error) error {
+	ctx := context.Background()
+	conn, err := m.pg.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate::Conn: %s", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", opts.LockID); err != nil {
+		return fmt.Errorf("migrate::pg_advisory_lock: %s", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", opts.LockID)
+
+	return fn()
+}
+
+func (m *Migrator) ensureSchema(table string) error {
+	stx, err := m.pg.Begin()
+	if err != nil {
+		return fmt.Errorf("ensureSchema::Begin: %s", err)
+	}
+
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version bigint PRIMARY KEY,
+		dirty boolean NOT NULL DEFAULT false,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`, table)
+	if err := stx.Execute(ddl); err != nil {
+		stx.Rollback()
+		return fmt.Errorf("ensureSchema::Execute: %s", err)
+	}
+	return stx.Commit()
+}
+
+func (m *Migrator) currentVersion(table string) (version int64, dirty bool, err error) {
+	stx, err := m.pg.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("currentVersion::Begin: %s", err)
+	}
+	defer stx.Rollback()
+
+	row, err := stx.QueryOne(fmt.Sprintf("SELECT version, dirty FROM %s ORDER BY version DESC LIMIT 1", table))
+	if err != nil {
+		if err.Error() == "empty record" {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("currentVersion::QueryOne: %s", err)
+	}
+	return row.ToInt64("version"), row.ToBoolean("dirty"), nil
+}
+
+func (m *Migrator) setVersion(table string, version int64, dirty bool) error {
+	stx, err := m.pg.Begin()
+	if err != nil {
+		return fmt.Errorf("setVersion::Begin: %s", err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (version, dirty, applied_at) VALUES ($1, $2, now())
+		ON CONFLICT (version) DO UPDATE SET dirty = $2, applied_at = now()`, table)
+	if err := stx.Execute(query, version, dirty); err != nil {
+		stx.Rollback()
+		return fmt.Errorf("setVersion::Execute: %s", err)
+	}
+	return stx.Commit()
+}
+
+func (m *Migrator) apply(table string, mig migration) error {
+	if err := m.setVersion(table, mig.Version, true); err != nil {
+		return err
+	}
+
+	stx, err := m.pg.Begin()
+	if err != nil {
+		return fmt.Errorf("apply::Begin(%d): %s", mig.Version, err)
+	}
+	if err := stx.Execute(mig.UpSQL); err != nil {
+		stx.Rollback()
+		return fmt.Errorf("apply:: version %d left dirty: %s", mig.Version, err)
+	}
+	if err := stx.Commit(); err != nil {
+		return fmt.Errorf("apply:: version %d left dirty: %s", mig.Version, err)
+	}
+
+	return m.setVersion(table, mig.Version, false)
+}
+
+func (m *Migrator) revert(table string, mig migration) error {
+	if mig.DownSQL == "" {
+		return fmt.Errorf("revert:: version %d has no down migration", mig.Version)
+	}
+	if err := m.setVersion(table, mig.Version, true); err != nil {
+		return err
+	}
+
+	stx, err := m.pg.Begin()
+	if err != nil {
+		return fmt.Errorf("revert::Begin(%d): %s", mig.Version, err)
+	}
+	if err := stx.Execute(mig.DownSQL); err != nil {
+		stx.Rollback()
+		return fmt.Errorf("revert:: version %d left dirty: %s", mig.Version, err)
+	}
+	if err := stx.Commit(); err != nil {
+		return fmt.Errorf("revert:: version %d left dirty: %s", mig.Version, err)
+	}
+
+	return m.deleteVersion(table, mig.Version)
+}
+
+func (m *Migrator) deleteVersion(table string, version int64) error {
+	stx, err := m.pg.Begin()
+	if err != nil {
+		return fmt.Errorf("deleteVersion::Begin: %s", err)
+	}
+	if err := stx.Execute(fmt.Sprintf("DELETE FROM %s WHERE version = $1", table), version); err != nil {
+		stx.Rollback()
+		return fmt.Errorf("deleteVersion::Execute: %s", err)
+	}
+	return stx.Commit()
+}
+
+var migrationFilename = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// loadMigrations reads every *.up.sql/*.down.sql file in source and
+// pairs them up by version, sorted ascending.
+func loadMigrations(source fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("loadMigrations::ReadDir: %s", err)
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := migrationFilename.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("loadMigrations:: %s: %s", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("loadMigrations::ReadFile(%s): %s", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if m[3] == "up" {
+			mig.UpSQL = string(content)
+		} else {
+			mig.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
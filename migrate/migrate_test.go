@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	source := fstest.MapFS{
+		"2_add_index.up.sql":      {Data: []byte("CREATE INDEX;")},
+		"2_add_index.down.sql":    {Data: []byte("DROP INDEX;")},
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users;")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"3_up_only.up.sql":        {Data: []byte("CREATE TABLE widgets;")},
+		"not_a_migration.txt":     {Data: []byte("ignore me")},
+		"README.md":               {Data: []byte("# docs")},
+	}
+
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("loadMigrations returned %d migrations, want 3", len(migrations))
+	}
+
+	for i, want := range []int64{1, 2, 3} {
+		if migrations[i].Version != want {
+			t.Fatalf("migrations[%d].Version = %d, want %d", i, migrations[i].Version, want)
+		}
+	}
+
+	if migrations[0].Name != "create_users" {
+		t.Fatalf("migrations[0].Name = %q, want %q", migrations[0].Name, "create_users")
+	}
+	if migrations[0].UpSQL != "CREATE TABLE users;" {
+		t.Fatalf("migrations[0].UpSQL = %q, want %q", migrations[0].UpSQL, "CREATE TABLE users;")
+	}
+	if migrations[0].DownSQL != "DROP TABLE users;" {
+		t.Fatalf("migrations[0].DownSQL = %q, want %q", migrations[0].DownSQL, "DROP TABLE users;")
+	}
+
+	if migrations[2].Name != "up_only" {
+		t.Fatalf("migrations[2].Name = %q, want %q", migrations[2].Name, "up_only")
+	}
+	if migrations[2].DownSQL != "" {
+		t.Fatalf("migrations[2].DownSQL = %q, want empty", migrations[2].DownSQL)
+	}
+}
+
+func TestLoadMigrationsLeadingZeroVersion(t *testing.T) {
+	source := fstest.MapFS{
+		"01_ok.up.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].Version != 1 {
+		t.Fatalf("loadMigrations = %+v, want single migration at version 1", migrations)
+	}
+}
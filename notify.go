@@ -0,0 +1,302 @@
+package daas
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// subscriberBuffer is the per-subscriber buffered channel size used by
+// Subscribe and Status. When a subscriber falls behind, the oldest
+// buffered event is dropped to make room for the newest one, and the
+// drop is counted so it's visible via Stats.
+const subscriberBuffer = 64
+
+// PGEvent is a single LISTEN/NOTIFY message delivered to a Subscribe
+// channel. PayloadJSON is populated on a best-effort basis since most
+// users push JSON payloads via pg_notify; it is nil when Payload isn't
+// a JSON object.
+type PGEvent struct {
+	Channel     string
+	Payload     string
+	PID         int32
+	PayloadJSON map[string]interface{}
+}
+
+// NotifyStatus mirrors pq's listener connection lifecycle so consumers
+// can resync state after a reconnect.
+type NotifyStatus int
+
+const (
+	NotifyStatusConnected NotifyStatus = iota
+	NotifyStatusDisconnected
+	NotifyStatusReconnected
+)
+
+// PGNotifyStats reports bounded-buffer overflow counts, keyed by
+// channel name (or "" for the Status channel).
+type PGNotifyStats struct {
+	Dropped map[string]uint64
+}
+
+type subscriber struct {
+	channel string
+	events  chan PGEvent
+	closed  bool
+}
+
+type statusSubscriber struct {
+	events chan NotifyStatus
+	closed bool
+}
+
+type PGNotify struct {
+	ln   *pq.Listener
+	fail chan error
+
+	mu       sync.Mutex
+	subs     map[string][]*subscriber
+	statuses []*statusSubscriber
+	dropped  map[string]uint64
+}
+
+func newPGNotify() *PGNotify {
+	return &PGNotify{
+		fail:    make(chan error, 2),
+		subs:    make(map[string][]*subscriber),
+		dropped: make(map[string]uint64),
+	}
+}
+
+func (pg *PGNotify) Ping() error {
+	return pg.ln.Ping()
+}
+
+// Subscribe starts (or joins) a LISTEN on channel and returns a
+// buffered channel of decoded events along with an unsubscribe func.
+// When the channel's last subscriber unsubscribes, the underlying
+// LISTEN is released. If a subscriber falls behind, the oldest queued
+// event is dropped to make room and the drop is counted in Stats.
+func (pg *PGNotify) Subscribe(channel string) (<-chan PGEvent, func(), error) {
+	pg.mu.Lock()
+
+	_, alreadyListening := pg.subs[channel]
+	if !alreadyListening {
+		Infof("LISTEN channel '%s'", channel)
+		if err := pg.ln.Listen(channel); err != nil {
+			pg.mu.Unlock()
+			return nil, nil, err
+		}
+	}
+
+	sub := &subscriber{channel: channel, events: make(chan PGEvent, subscriberBuffer)}
+	pg.subs[channel] = append(pg.subs[channel], sub)
+	pg.mu.Unlock()
+
+	// unsubscribe holds pg.mu for the whole Listen/Unlisten bookkeeping
+	// so a concurrent Subscribe on the same channel can't race with it
+	// (e.g. Unlisten running after a fresh Listen and silently dropping
+	// the subscription). The channel is closed once removed so a
+	// `for e := range events` consumer (like the Listen compatibility
+	// wrapper below) terminates instead of leaking its goroutine. The
+	// closed guard makes a second call (e.g. an explicit call plus a
+	// deferred one) a no-op instead of a "close of closed channel" panic.
+	unsubscribe := func() {
+		pg.mu.Lock()
+		defer pg.mu.Unlock()
+
+		if sub.closed {
+			return
+		}
+		sub.closed = true
+
+		remaining := pg.subs[channel][:0]
+		for _, s := range pg.subs[channel] {
+			if s != sub {
+				remaining = append(remaining, s)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(pg.subs, channel)
+			if err := pg.ln.Unlisten(channel); err != nil {
+				Errorf("Unlisten:: %s", err)
+			}
+		} else {
+			pg.subs[channel] = remaining
+		}
+		close(sub.events)
+	}
+
+	return sub.events, unsubscribe, nil
+}
+
+// Status returns a channel of listener connection lifecycle events, so
+// callers can resync their state after a NotifyStatusReconnected.
+func (pg *PGNotify) Status() (<-chan NotifyStatus, func()) {
+	sub := &statusSubscriber{events: make(chan NotifyStatus, subscriberBuffer)}
+
+	pg.mu.Lock()
+	pg.statuses = append(pg.statuses, sub)
+	pg.mu.Unlock()
+
+	unsubscribe := func() {
+		pg.mu.Lock()
+		defer pg.mu.Unlock()
+
+		if sub.closed {
+			return
+		}
+		sub.closed = true
+
+		remaining := pg.statuses[:0]
+		for _, s := range pg.statuses {
+			if s != sub {
+				remaining = append(remaining, s)
+			}
+		}
+		pg.statuses = remaining
+		close(sub.events)
+	}
+
+	return sub.events, unsubscribe
+}
+
+// Stats reports, per channel, how many events were dropped because a
+// subscriber's buffer was full.
+func (pg *PGNotify) Stats() PGNotifyStats {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	dropped := make(map[string]uint64, len(pg.dropped))
+	for k, v := range pg.dropped {
+		dropped[k] = v
+	}
+	return PGNotifyStats{Dropped: dropped}
+}
+
+// Listen is a compatibility wrapper around Subscribe for callers that
+// just want a single callback invoked per notification.
+func (pg *PGNotify) Listen(channelName string, eventCallback func(e *pq.Notification)) error {
+	events, _, err := pg.Subscribe(channelName)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for e := range events {
+			eventCallback(&pq.Notification{Channel: e.Channel, Extra: e.Payload, BePid: int(e.PID)})
+		}
+	}()
+
+	return nil
+}
+
+// Close shuts down the underlying listener and releases every
+// outstanding Subscribe/Status channel, so a goroutine blocked in
+// `for e := range events` (e.g. the Listen compatibility wrapper)
+// doesn't leak forever when the caller closes the notifier without
+// having unsubscribed everything first.
+func (pg *PGNotify) Close() error {
+	pg.mu.Lock()
+	for channel, subs := range pg.subs {
+		for _, sub := range subs {
+			if !sub.closed {
+				sub.closed = true
+				close(sub.events)
+			}
+		}
+		delete(pg.subs, channel)
+	}
+	for _, sub := range pg.statuses {
+		if !sub.closed {
+			sub.closed = true
+			close(sub.events)
+		}
+	}
+	pg.statuses = nil
+	pg.mu.Unlock()
+
+	close(pg.fail)
+	return pg.ln.Close()
+}
+
+// dispatchLoop reads raw notifications off the listener connection and
+// fans them out to per-channel subscribers. A nil notification marks a
+// successful reconnection, per pq.Listener's documented behaviour; the
+// listener's event callback in CreateChannel has already reported it
+// to Status subscribers, so it is skipped here.
+func (pg *PGNotify) dispatchLoop() {
+	for {
+		select {
+		case e, ok := <-pg.ln.Notify:
+			if !ok {
+				return
+			}
+			if e == nil {
+				continue
+			}
+			pg.dispatch(e)
+		case <-time.After(time.Minute * 5):
+			go pg.ln.Ping()
+		}
+	}
+}
+
+func (pg *PGNotify) dispatch(n *pq.Notification) {
+	event := PGEvent{Channel: n.Channel, Payload: n.Extra, PID: int32(n.BePid)}
+	if looksLikeJSON([]byte(n.Extra)) {
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(n.Extra), &payload); err == nil {
+			event.PayloadJSON = payload
+		}
+	}
+
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	for _, sub := range pg.subs[n.Channel] {
+		pg.sendLocked(sub.events, event, n.Channel)
+	}
+}
+
+func (pg *PGNotify) dispatchStatus(e pq.ListenerEventType) {
+	status := NotifyStatusConnected
+	switch e {
+	case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+		status = NotifyStatusDisconnected
+	case pq.ListenerEventReconnected:
+		status = NotifyStatusReconnected
+	}
+
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	for _, sub := range pg.statuses {
+		select {
+		case sub.events <- status:
+		default:
+			<-sub.events
+			sub.events <- status
+			pg.dropped[""]++
+		}
+	}
+}
+
+// sendLocked delivers event to ch, dropping the oldest queued event and
+// counting it against channel if ch's buffer is full. Callers must hold
+// pg.mu.
+func (pg *PGNotify) sendLocked(ch chan PGEvent, event PGEvent, channel string) {
+	select {
+	case ch <- event:
+	default:
+		<-ch
+		ch <- event
+		pg.dropped[channel]++
+	}
+}
+
+func looksLikeJSON(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) > 0 && (b[0] == '{' || b[0] == '[')
+}
@@ -0,0 +1,36 @@
+package daas
+
+import "time"
+
+// Observer lets callers wire query metrics or tracing into daas
+// without modifying it directly — set PGClient.Observer to receive a
+// start/end callback around every Query/Execute. See the prom
+// sub-package for a ready-made Prometheus-backed implementation.
+type Observer interface {
+	OnQueryStart(query string)
+	OnQueryEnd(query string, err error, rowsAffected int64, duration time.Duration)
+
+	// OnQueryDebug is called between OnQueryStart and OnQueryEnd, only
+	// for the *Print methods (QueryPrint, QueryOnePrint, ExecutePrint),
+	// so the verbose query text can be logged. Implementations that
+	// don't want the dump, such as PromObserver, can make this a no-op.
+	OnQueryDebug(query string, args []interface{}, duration time.Duration)
+}
+
+// legacyObserver reproduces the EstimatedPrint/sqlQuery logging that
+// used to be hard-coded into sctxQuery/sctxExecute, now sourced from
+// the Observer hook instead. It is used whenever PGClient.Observer is
+// nil.
+type legacyObserver struct{}
+
+func (legacyObserver) OnQueryStart(query string) {}
+
+func (legacyObserver) OnQueryEnd(query string, err error, rowsAffected int64, duration time.Duration) {
+	Infof("Elapsed time %d ms estimated.", duration.Milliseconds())
+}
+
+func (legacyObserver) OnQueryDebug(query string, args []interface{}, duration time.Duration) {
+	sqlQuery(duration, query, args...)
+}
+
+var defaultObserver Observer = legacyObserver{}
@@ -3,12 +3,14 @@ package daas
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lib/pq"
@@ -36,7 +38,18 @@ func getDSN(appName string) string {
 }
 
 type PGClient struct {
-	DB  *sql.DB
+	DB *sql.DB
+	// Observer, when set, is notified around every Query/Execute run
+	// through this client's transactions. Defaults to an internal
+	// observer that reproduces the old EstimatedPrint timing log.
+	Observer Observer
+
+	// ctx is the context used by Query/Execute when no explicit
+	// context is given.
+	//
+	// Deprecated: pass a context.Context explicitly via QueryCtx,
+	// ExecuteCtx and QueryOneCtx instead of relying on the context
+	// captured at Connect time.
 	ctx *context.Context
 }
 
@@ -44,64 +57,43 @@ type PGRow map[string]string
 type PGRecord []PGRow
 
 type PGTx struct {
-	Closed bool
-	tx     *sql.Tx
-	ctx    *context.Context
+	Closed   bool
+	ReadOnly bool
+	tx       *sql.Tx
+	ctx      *context.Context
+	observer Observer
 }
 
-type PGNotify struct {
-	ln   *pq.Listener
-	fail chan error
+// PGTxOptions configures the isolation level, read-only mode and
+// deferrability of a transaction started via PGClient.BeginTx.
+type PGTxOptions struct {
+	Isolation  sql.IsolationLevel
+	ReadOnly   bool
+	Deferrable bool
 }
 
-func (pg *PGNotify) Ping() error {
-	return pg.ln.Ping()
-}
 func (pg *PGClient) CreateChannel(appTitle string) (*PGNotify, error) {
-	n := &PGNotify{fail: make(chan error, 2)}
+	n := newPGNotify()
 
+	var connected sync.Once
 	n.ln = pq.NewListener(getDSN(appTitle), 5*time.Second, time.Minute, func(e pq.ListenerEventType, err error) {
 		if err != nil {
 			Errorf("Listen:: %s", err)
 		}
-		if e == pq.ListenerEventConnectionAttemptFailed {
-			n.fail <- err
-		} else {
-			n.fail <- nil
-		}
+		connected.Do(func() {
+			if e == pq.ListenerEventConnectionAttemptFailed {
+				n.fail <- err
+			} else {
+				n.fail <- nil
+			}
+		})
+		n.dispatchStatus(e)
 	})
 	err := <-n.fail
 	Infof("'listen::%s/%s' Consumed", os.Getenv(PGHOST), os.Getenv(PGDATABASE))
-	return n, err
-}
 
-func (pg *PGNotify) Listen(channelName string, eventCallback func(e *pq.Notification)) error {
-	Infof("LISTEN channel '%s'", channelName)
-	if err := pg.ln.Listen(channelName); err != nil {
-		pg.ln.Close()
-		return err
-	}
-
-	go func() {
-		for {
-			select {
-			case e := <-pg.ln.Notify:
-				if e == nil {
-					continue
-				}
-				eventCallback(e)
-			case <-time.After(time.Minute * 5):
-				go pg.ln.Ping()
-			}
-		}
-	}()
-
-	return nil
-}
-
-func (pg *PGNotify) Close() error {
-	close(pg.fail)
-	return pg.ln.Close()
+	go n.dispatchLoop()
+	return n, err
 }
 
 func (pg *PGClient) Connect(c *context.Context, appTitle string) {
@@ -178,12 +170,160 @@ func (pg PGRow) ToTime(name string) time.Time {
 	return data
 }
 
+// ToJSON unmarshals a JSONB column into v, which should be a pointer.
+func (pg PGRow) ToJSON(name string, v interface{}) error {
+	if err := json.Unmarshal([]byte(pg[name]), v); err != nil {
+		return fmt.Errorf("PGRow.ToJSON('%s'): %s", name, err)
+	}
+	return nil
+}
+
+// ToInt64Array decodes a PostgreSQL integer array column, as produced
+// by fetchRow for int2[]/int4[]/int8[] columns.
+func (pg PGRow) ToInt64Array(name string) []int64 {
+	data := []int64{}
+	if err := json.Unmarshal([]byte(pg[name]), &data); err != nil {
+		Errorf("PGRow.ToInt64Array('%s'): %s", name, err)
+	}
+	return data
+}
+
+// ToStringArray decodes a PostgreSQL text/varchar array column, as
+// produced by fetchRow for text[]/varchar[] columns.
+func (pg PGRow) ToStringArray(name string) []string {
+	data := []string{}
+	if err := json.Unmarshal([]byte(pg[name]), &data); err != nil {
+		Errorf("PGRow.ToStringArray('%s'): %s", name, err)
+	}
+	return data
+}
+
 func (pg *PGClient) Begin() (*PGTx, error) {
+	return pg.BeginTx(nil)
+}
+
+// BeginTx starts a transaction with an explicit isolation level and
+// read-only/deferrable mode. A nil opts behaves like Begin, using
+// sql.LevelDefault in read-write mode.
+//
+// Deferrable only has an effect when paired with Serializable and
+// ReadOnly — PostgreSQL ignores SET TRANSACTION DEFERRABLE otherwise.
+// For long-running analytic queries that must observe a consistent
+// snapshot without blocking writers, pass:
+//
+//	pg.BeginTx(&daas.PGTxOptions{Isolation: sql.LevelSerializable, ReadOnly: true, Deferrable: true})
+func (pg *PGClient) BeginTx(opts *PGTxOptions) (*PGTx, error) {
 	// defer EstimatedPrint(time.Now(), fmt.Sprintf("Begin: %+v", pg.ctx))
-	stx, err := pg.DB.BeginTx(*pg.ctx, &sql.TxOptions{Isolation: sql.LevelDefault})
+	if opts == nil {
+		opts = &PGTxOptions{Isolation: sql.LevelDefault}
+	}
+
+	stx, err := pg.DB.BeginTx(*pg.ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Deferrable {
+		if _, err := stx.ExecContext(*pg.ctx, "SET TRANSACTION DEFERRABLE"); err != nil {
+			stx.Rollback()
+			return nil, fmt.Errorf("BeginTx::SET TRANSACTION DEFERRABLE: %s", err)
+		}
+	}
+
+	observer := pg.Observer
+	if observer == nil {
+		observer = defaultObserver
+	}
+
+	pgx := PGTx{tx: stx, ctx: pg.ctx, ReadOnly: opts.ReadOnly, observer: observer}
+	return &pgx, nil
+}
+
+const (
+	sqlstateSerializationFailure = "40001"
+	sqlstateDeadlockDetected     = "40P01"
+)
+
+// RetryOption configures the retry behaviour of PGClient.WithTransaction.
+type RetryOption func(*retryConfig)
 
-	pgx := PGTx{tx: stx, ctx: pg.ctx}
-	return &pgx, err
+type retryConfig struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	txOpts      *PGTxOptions
+}
+
+// WithMaxAttempts caps the number of times WithTransaction will run fn,
+// including the first attempt. Defaults to 5.
+func WithMaxAttempts(n int) RetryOption {
+	return func(c *retryConfig) { c.maxAttempts = n }
+}
+
+// WithBaseDelay sets the base of the exponential backoff between
+// retries. Defaults to 50ms, doubling on each subsequent attempt.
+func WithBaseDelay(d time.Duration) RetryOption {
+	return func(c *retryConfig) { c.baseDelay = d }
+}
+
+// WithTxOptions makes WithTransaction open its transaction via
+// pg.BeginTx(txOpts) instead of pg.Begin(), so callers can pair the
+// retry loop with an explicit isolation level — e.g. Serializable for
+// correctness-critical writes, which is what automatic retry on
+// serialization_failure exists to make safe.
+func WithTxOptions(txOpts *PGTxOptions) RetryOption {
+	return func(c *retryConfig) { c.txOpts = txOpts }
+}
+
+// isRetryablePGError reports whether err is a serialization_failure or
+// deadlock_detected error, as identified by its SQLSTATE code.
+func isRetryablePGError(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return false
+	}
+	switch pqErr.Code {
+	case sqlstateSerializationFailure, sqlstateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// WithTransaction runs fn inside a Begin/Commit pair, automatically
+// rolling back and retrying with exponential backoff when PostgreSQL
+// reports a serialization_failure (40001) or deadlock_detected (40P01).
+// This makes it safe to run correctness-critical writes under
+// Serializable isolation without every caller hand-rolling retry logic.
+func (pg *PGClient) WithTransaction(fn func(*PGTx) error, opts ...RetryOption) error {
+	cfg := retryConfig{maxAttempts: 5, baseDelay: 50 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var err error
+	for attempt := 0; attempt < cfg.maxAttempts; attempt++ {
+		var stx *PGTx
+		stx, err = pg.BeginTx(cfg.txOpts)
+		if err != nil {
+			return fmt.Errorf("WithTransaction::Begin: %s", err)
+		}
+
+		if err = fn(stx); err == nil {
+			if err = stx.Commit(); err == nil {
+				return nil
+			}
+		} else {
+			stx.Rollback()
+		}
+
+		if !isRetryablePGError(err) {
+			return err
+		}
+
+		Infof("WithTransaction:: retrying after %s (attempt %d/%d)", err, attempt+1, cfg.maxAttempts)
+		time.Sleep(cfg.baseDelay * time.Duration(1<<uint(attempt)))
+	}
+
+	return fmt.Errorf("WithTransaction:: exhausted retries: %s", err)
 }
 
 func (stx *PGTx) Commit() error {
@@ -197,7 +337,7 @@ func (stx *PGTx) Rollback() error {
 }
 
 func (stx *PGTx) QueryOne(query string, args ...interface{}) (PGRow, error) {
-	rows, err := sctxQuery(stx.tx, stx.ctx, false, query, args...)
+	rows, err := sctxQuery(stx.tx, stx.ctx, stx.observer, false, query, args...)
 
 	if err != nil {
 		return nil, fmt.Errorf("QueryOne::%s", err.Error())
@@ -209,8 +349,24 @@ func (stx *PGTx) QueryOne(query string, args ...interface{}) (PGRow, error) {
 	return fetchRow(rows)
 }
 
+// QueryOneCtx is QueryOne with an explicit per-call context, so a
+// caller can cancel or bound a single query without depending on the
+// context captured at Connect time.
+func (stx *PGTx) QueryOneCtx(ctx context.Context, query string, args ...interface{}) (PGRow, error) {
+	rows, err := sctxQuery(stx.tx, &ctx, stx.observer, false, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("QueryOneCtx::%s", err.Error())
+	}
+	if !rows.Next() {
+		return nil, fmt.Errorf("empty record")
+	}
+	defer rows.Close()
+	return fetchRow(rows)
+}
+
 func (stx *PGTx) QueryOnePrint(query string, args ...interface{}) (PGRow, error) {
-	rows, err := sctxQuery(stx.tx, stx.ctx, true, query, args...)
+	rows, err := sctxQuery(stx.tx, stx.ctx, stx.observer, true, query, args...)
 
 	if err != nil {
 		return nil, fmt.Errorf("QueryOne::%s", err.Error())
@@ -223,19 +379,87 @@ func (stx *PGTx) QueryOnePrint(query string, args ...interface{}) (PGRow, error)
 }
 
 func (stx *PGTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return sctxQuery(stx.tx, stx.ctx, false, query, args...)
+	return sctxQuery(stx.tx, stx.ctx, stx.observer, false, query, args...)
 }
 
 func (stx *PGTx) QueryPrint(query string, args ...interface{}) (*sql.Rows, error) {
-	return sctxQuery(stx.tx, stx.ctx, true, query, args...)
+	return sctxQuery(stx.tx, stx.ctx, stx.observer, true, query, args...)
+}
+
+// QueryCtx is Query with an explicit per-call context, so a caller can
+// cancel or bound a single query without depending on the context
+// captured at Connect time.
+func (stx *PGTx) QueryCtx(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return sctxQuery(stx.tx, &ctx, stx.observer, false, query, args...)
+}
+
+// QueryOneInto runs query and scans the first row into dst, which must
+// be a pointer to a struct. Struct fields are matched to columns via
+// their `db:"colname"` tag; fields without a matching tag are ignored.
+// Pointer fields receive nil for NULL columns, and slice fields (other
+// than []byte) are scanned via pq.Array.
+func (stx *PGTx) QueryOneInto(dst interface{}, query string, args ...interface{}) error {
+	rows, err := sctxQuery(stx.tx, stx.ctx, stx.observer, false, query, args...)
+	if err != nil {
+		return fmt.Errorf("QueryOneInto::%s", err.Error())
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return fmt.Errorf("empty record")
+	}
+	return scanStructInto(rows, dst)
+}
+
+// QueryInto runs query and scans every row into dst, which must be a
+// pointer to a slice of struct. See QueryOneInto for the tagging and
+// type-handling rules applied to each row.
+func (stx *PGTx) QueryInto(dst interface{}, query string, args ...interface{}) error {
+	rows, err := sctxQuery(stx.tx, stx.ctx, stx.observer, false, query, args...)
+	if err != nil {
+		return fmt.Errorf("QueryInto::%s", err.Error())
+	}
+	defer rows.Close()
+
+	dstVal := reflect.ValueOf(dst)
+	if dstVal.Kind() != reflect.Ptr || dstVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("QueryInto:: dst must be a pointer to a slice of struct")
+	}
+
+	sliceVal := dstVal.Elem()
+	elemType := sliceVal.Type().Elem()
+	for rows.Next() {
+		elemPtr := reflect.New(elemType)
+		if err := scanStructInto(rows, elemPtr.Interface()); err != nil {
+			return err
+		}
+		sliceVal.Set(reflect.Append(sliceVal, elemPtr.Elem()))
+	}
+	return rows.Err()
 }
 
 func (stx *PGTx) Execute(query string, args ...interface{}) error {
-	return sctxExecute(stx.tx, stx.ctx, false, query, args...)
+	if stx.ReadOnly {
+		return fmt.Errorf("Execute:: transaction is read-only")
+	}
+	return sctxExecute(stx.tx, stx.ctx, stx.observer, false, query, args...)
+}
+
+// ExecuteCtx is Execute with an explicit per-call context, so a caller
+// can cancel or bound a single statement without depending on the
+// context captured at Connect time.
+func (stx *PGTx) ExecuteCtx(ctx context.Context, query string, args ...interface{}) error {
+	if stx.ReadOnly {
+		return fmt.Errorf("ExecuteCtx:: transaction is read-only")
+	}
+	return sctxExecute(stx.tx, &ctx, stx.observer, false, query, args...)
 }
 
 func (stx *PGTx) ExecutePrint(query string, args ...interface{}) error {
-	return sctxExecute(stx.tx, stx.ctx, true, query, args...)
+	if stx.ReadOnly {
+		return fmt.Errorf("ExecutePrint:: transaction is read-only")
+	}
+	return sctxExecute(stx.tx, stx.ctx, stx.observer, true, query, args...)
 }
 
 func (stx *PGTx) FetchRow(rows *sql.Rows) (PGRow, error) {
@@ -276,17 +500,140 @@ func (row PGRecord) Find(columnName string, compareValue string) bool {
 	return false
 }
 
+// scanStructInto scans the current row of rows into dst, which must be
+// a pointer to a struct, matching columns to fields via `db` tags.
+func scanStructInto(rows *sql.Rows, dst interface{}) error {
+	dstVal := reflect.ValueOf(dst)
+	if dstVal.Kind() != reflect.Ptr || dstVal.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("scanStructInto:: dst must be a pointer to a struct")
+	}
+	structVal := dstVal.Elem()
+	structType := structVal.Type()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("scanStructInto::Columns: %s", err)
+	}
+
+	fieldByCol := make(map[string]reflect.Value, structType.NumField())
+	for i := 0; i < structType.NumField(); i++ {
+		tag := structType.Field(i).Tag.Get("db")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fieldByCol[tag] = structVal.Field(i)
+	}
+
+	var discard interface{}
+	pointers := make([]interface{}, len(columns))
+	for i, col := range columns {
+		field, ok := fieldByCol[col]
+		if !ok {
+			pointers[i] = &discard
+			continue
+		}
+		pointers[i] = scanTarget(field)
+	}
+
+	if err := rows.Scan(pointers...); err != nil {
+		return fmt.Errorf("scanStructInto::Scan: %s", err)
+	}
+	return nil
+}
+
+// scanTarget returns a sql.Rows.Scan destination appropriate for field:
+// pointer fields get a nullableScanner so NULL columns become nil,
+// []byte fields scan directly, other slice fields go through pq.Array,
+// and everything else (including sql.NullString/time.Time/sql.Scanner
+// implementations) is scanned via its address.
+func scanTarget(field reflect.Value) interface{} {
+	switch {
+	case field.Kind() == reflect.Ptr:
+		return &nullableScanner{field: field}
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8:
+		return pq.Array(field.Addr().Interface())
+	default:
+		return field.Addr().Interface()
+	}
+}
+
+// nullableScanner implements sql.Scanner on behalf of a pointer struct
+// field, so that NULL columns leave the field nil and non-NULL values
+// are converted into a freshly allocated pointee.
+type nullableScanner struct {
+	field reflect.Value
+}
+
+func (n *nullableScanner) Scan(src interface{}) error {
+	if src == nil {
+		n.field.Set(reflect.Zero(n.field.Type()))
+		return nil
+	}
+
+	ptr := reflect.New(n.field.Type().Elem())
+	if err := assignScanValue(ptr.Elem(), src); err != nil {
+		return err
+	}
+	n.field.Set(ptr)
+	return nil
+}
+
+// assignScanValue converts a driver value src into dst, which must be
+// addressable. It handles direct type conversion and the common
+// []byte-to-string case produced by the postgres driver.
+func assignScanValue(dst reflect.Value, src interface{}) error {
+	if b, ok := src.([]byte); ok && dst.Kind() == reflect.String {
+		dst.SetString(string(b))
+		return nil
+	}
+
+	srcVal := reflect.ValueOf(src)
+	if !srcVal.Type().ConvertibleTo(dst.Type()) {
+		return fmt.Errorf("assignScanValue:: cannot scan %T into %s", src, dst.Type())
+	}
+	dst.Set(srcVal.Convert(dst.Type()))
+	return nil
+}
+
+// pgArrayTarget returns a pointer to the pq array type that matches a
+// PostgreSQL array column's underlying element type, or nil if column
+// isn't one of the array types fetchRow knows how to decode. udtName is
+// compared case-insensitively: lib/pq's DatabaseTypeName() reports the
+// oid.TypeName table values, which are uppercase ("_BOOL", "_INT4", ...).
+func pgArrayTarget(udtName string) interface{} {
+	switch strings.ToUpper(udtName) {
+	case "_BOOL":
+		return new(pq.BoolArray)
+	case "_INT2", "_INT4", "_INT8":
+		return new(pq.Int64Array)
+	case "_FLOAT4", "_FLOAT8":
+		return new(pq.Float64Array)
+	case "_TEXT", "_VARCHAR":
+		return new(pq.StringArray)
+	default:
+		return nil
+	}
+}
+
 func fetchRow(rows *sql.Rows) (PGRow, error) {
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, fmt.Errorf("FetchRow::Columns::%s", err)
 	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("FetchRow::ColumnTypes::%s", err)
+	}
 
 	resultMap := make(PGRow)
 	values := make([]interface{}, len(columns))
 	pointers := make([]interface{}, len(columns))
 	for i := range values {
-		pointers[i] = &values[i]
+		if target := pgArrayTarget(columnTypes[i].DatabaseTypeName()); target != nil {
+			pointers[i] = target
+		} else {
+			pointers[i] = &values[i]
+		}
 	}
 	err = rows.Scan(pointers...)
 	if err == sql.ErrNoRows {
@@ -295,6 +642,19 @@ func fetchRow(rows *sql.Rows) (PGRow, error) {
 		return nil, fmt.Errorf("FetchRow::Scan: %s", err)
 	}
 
+	for i := range columns {
+		switch ptr := pointers[i].(type) {
+		case *pq.BoolArray:
+			values[i] = *ptr
+		case *pq.Int64Array:
+			values[i] = *ptr
+		case *pq.Float64Array:
+			values[i] = *ptr
+		case *pq.StringArray:
+			values[i] = *ptr
+		}
+	}
+
 	for i, val := range values {
 		if reflect.TypeOf(val) == nil {
 			resultMap[columns[i]] = ""
@@ -308,11 +668,21 @@ func fetchRow(rows *sql.Rows) (PGRow, error) {
 		case "string":
 			resultMap[columns[i]] = val.(string)
 		case "[]uint8":
+			// JSONB columns arrive as raw bytes; store them verbatim so
+			// ToJSON/ToByte can consume them without a round trip.
 			resultMap[columns[i]] = string(val.([]uint8))
 		case "bool":
 			resultMap[columns[i]] = fmt.Sprintf("%t", val.(bool))
 		case "time.Time":
 			resultMap[columns[i]] = val.(time.Time).Format(time.RFC3339Nano)
+		case "pq.BoolArray", "pq.Int64Array", "pq.Float64Array", "pq.StringArray":
+			b, jerr := json.Marshal(val)
+			if jerr != nil {
+				Errorf("fetchRow:: json.Marshal('%s'): %s", columns[i], jerr)
+				resultMap[columns[i]] = "[]"
+				continue
+			}
+			resultMap[columns[i]] = string(b)
 		default:
 			Errorf("Reflect TypeOf: %s ", reflect.TypeOf(val).String())
 			resultMap[columns[i]] = ""
@@ -321,36 +691,43 @@ func fetchRow(rows *sql.Rows) (PGRow, error) {
 	return resultMap, nil
 }
 
-func sctxQuery(pgstx *sql.Tx, pgctx *context.Context, envDebug bool, query string, args ...interface{}) (*sql.Rows, error) {
+func sctxQuery(pgstx *sql.Tx, pgctx *context.Context, observer Observer, envDebug bool, query string, args ...interface{}) (*sql.Rows, error) {
 	elapsed := time.Now()
-	if envDebug {
-		defer sqlQuery(elapsed, query, args...)
-	}
-	defer EstimatedPrint(elapsed, "Query")
 
+	observer.OnQueryStart(query)
 	rows, err := pgstx.QueryContext(*pgctx, query, args...)
+	duration := time.Since(elapsed)
+	if envDebug {
+		observer.OnQueryDebug(query, args, duration)
+	}
+	observer.OnQueryEnd(query, err, 0, duration)
 	if err != nil {
 		return nil, err
 	}
 	return rows, nil
 }
 
-func sctxExecute(pgstx *sql.Tx, pgctx *context.Context, envDebug bool, query string, args ...interface{}) error {
+func sctxExecute(pgstx *sql.Tx, pgctx *context.Context, observer Observer, envDebug bool, query string, args ...interface{}) error {
 	elapsed := time.Now()
+
+	observer.OnQueryStart(query)
+	result, err := pgstx.ExecContext(*pgctx, query, args...)
+	duration := time.Since(elapsed)
+	var rowsAffected int64
+	if err == nil {
+		rowsAffected, _ = result.RowsAffected()
+	}
 	if envDebug {
-		defer sqlQuery(elapsed, query, args...)
+		observer.OnQueryDebug(query, args, duration)
 	}
-
-	defer EstimatedPrint(elapsed, "Execute")
-
-	_, err := pgstx.ExecContext(*pgctx, query, args...)
+	observer.OnQueryEnd(query, err, rowsAffected, duration)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func sqlQuery(elapsed time.Time, query string, args ...interface{}) {
+func sqlQuery(duration time.Duration, query string, args ...interface{}) {
 	for i, arg := range args {
 		rgx := regexp.MustCompile(fmt.Sprintf(`\$%d`, i+1))
 		query = rgx.ReplaceAllString(query, "'"+arg.(string)+"'")
@@ -366,7 +743,7 @@ func sqlQuery(elapsed time.Time, query string, args ...interface{}) {
 		}
 		logNone.Println(strings.ReplaceAll(line, "\t", "  "))
 	}
-	logNone.Printf("\nElapsed time %d ms estimated.", Estimated(elapsed))
+	logNone.Printf("\nElapsed time %d ms estimated.", duration.Milliseconds())
 	logNone.Printf("\n[Query]")
 }
 func leadingSpace(line string) int {
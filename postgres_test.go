@@ -0,0 +1,116 @@
+package daas
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestAssignScanValue(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     interface{}
+		dst     interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "bytes into string", src: []byte("hello"), dst: new(string), want: "hello"},
+		{name: "int64 into int64", src: int64(42), dst: new(int64), want: int64(42)},
+		{name: "int64 into int", src: int64(7), dst: new(int), want: 7},
+		{name: "incompatible type", src: []byte("hello"), dst: new(int), wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dstVal := reflect.ValueOf(tc.dst).Elem()
+			err := assignScanValue(dstVal, tc.src)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("assignScanValue(%v) = nil error, want error", tc.src)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("assignScanValue(%v) = %v, want nil", tc.src, err)
+			}
+			if got := dstVal.Interface(); got != tc.want {
+				t.Fatalf("assignScanValue(%v) set %v, want %v", tc.src, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNullableScannerScan(t *testing.T) {
+	t.Run("nil src leaves field nil", func(t *testing.T) {
+		var field *string
+		fieldVal := reflect.ValueOf(&field).Elem()
+		scanner := &nullableScanner{field: fieldVal}
+
+		if err := scanner.Scan(nil); err != nil {
+			t.Fatalf("Scan(nil) = %v, want nil", err)
+		}
+		if field != nil {
+			t.Fatalf("field = %v, want nil", field)
+		}
+	})
+
+	t.Run("non-nil src allocates pointee", func(t *testing.T) {
+		var field *string
+		fieldVal := reflect.ValueOf(&field).Elem()
+		scanner := &nullableScanner{field: fieldVal}
+
+		if err := scanner.Scan([]byte("hi")); err != nil {
+			t.Fatalf("Scan([]byte) = %v, want nil", err)
+		}
+		if field == nil || *field != "hi" {
+			t.Fatalf("field = %v, want pointer to \"hi\"", field)
+		}
+	})
+
+	t.Run("incompatible src returns error", func(t *testing.T) {
+		var field *int
+		fieldVal := reflect.ValueOf(&field).Elem()
+		scanner := &nullableScanner{field: fieldVal}
+
+		if err := scanner.Scan([]byte("not a number")); err == nil {
+			t.Fatal("Scan([]byte) = nil error, want error")
+		}
+	})
+}
+
+func TestPgArrayTarget(t *testing.T) {
+	cases := []struct {
+		udtName string
+		want    interface{}
+	}{
+		// lib/pq's DatabaseTypeName() reports these uppercase.
+		{"_BOOL", new(pq.BoolArray)},
+		{"_INT2", new(pq.Int64Array)},
+		{"_INT4", new(pq.Int64Array)},
+		{"_INT8", new(pq.Int64Array)},
+		{"_FLOAT4", new(pq.Float64Array)},
+		{"_FLOAT8", new(pq.Float64Array)},
+		{"_TEXT", new(pq.StringArray)},
+		{"_VARCHAR", new(pq.StringArray)},
+		// lowercase must still match.
+		{"_bool", new(pq.BoolArray)},
+		{"_text", new(pq.StringArray)},
+		{"jsonb", nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.udtName, func(t *testing.T) {
+			got := pgArrayTarget(tc.udtName)
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("pgArrayTarget(%q) = %#v, want nil", tc.udtName, got)
+				}
+				return
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tc.want) {
+				t.Fatalf("pgArrayTarget(%q) = %T, want %T", tc.udtName, got, tc.want)
+			}
+		})
+	}
+}
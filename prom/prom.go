@@ -0,0 +1,99 @@
+// Package prom is a ready-made daas.Observer that exports query
+// duration histograms, connection-pool gauges and LISTEN reconnect
+// counters to Prometheus, so users don't have to wire their own.
+package prom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/touno-io/goasa"
+)
+
+// PromObserver implements daas.Observer and registers its metrics with
+// the default Prometheus registry on construction.
+type PromObserver struct {
+	duration   *prometheus.HistogramVec
+	reconnects prometheus.Counter
+}
+
+// NewPromObserver builds and registers a PromObserver for pg, prefixed
+// with namespace. Assign the result to pg.Observer to start recording:
+//
+//	pg.Observer = prom.NewPromObserver(pg, "myapp")
+func NewPromObserver(pg *daas.PGClient, namespace string) *PromObserver {
+	p := &PromObserver{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "daas",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of daas queries and executes, by outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"outcome"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "daas",
+			Name:      "listen_reconnects_total",
+			Help:      "Number of times a PGNotify listener reconnected.",
+		}),
+	}
+
+	poolOpen := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "daas",
+		Name:      "pool_open_connections",
+		Help:      "Open connections in the PGClient pool.",
+	}, func() float64 { return float64(pg.DB.Stats().OpenConnections) })
+
+	poolInUse := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "daas",
+		Name:      "pool_in_use_connections",
+		Help:      "In-use connections in the PGClient pool.",
+	}, func() float64 { return float64(pg.DB.Stats().InUse) })
+
+	poolIdle := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "daas",
+		Name:      "pool_idle_connections",
+		Help:      "Idle connections in the PGClient pool.",
+	}, func() float64 { return float64(pg.DB.Stats().Idle) })
+
+	for _, c := range []prometheus.Collector{p.duration, p.reconnects, poolOpen, poolInUse, poolIdle} {
+		if err := prometheus.Register(c); err != nil {
+			if _, already := err.(prometheus.AlreadyRegisteredError); !already {
+				panic(err)
+			}
+		}
+	}
+	return p
+}
+
+func (p *PromObserver) OnQueryStart(query string) {}
+
+func (p *PromObserver) OnQueryEnd(query string, err error, rowsAffected int64, duration time.Duration) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	p.duration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+// OnQueryDebug deliberately does nothing — PromObserver only records
+// metrics, it never dumps query text.
+func (p *PromObserver) OnQueryDebug(query string, args []interface{}, duration time.Duration) {}
+
+// ObserveReconnect increments the LISTEN reconnect counter. Wire it up
+// to a PGNotify's Status channel:
+//
+//	status, _ := notify.Status()
+//	go func() {
+//	    for s := range status {
+//	        if s == daas.NotifyStatusReconnected {
+//	            promObserver.ObserveReconnect()
+//	        }
+//	    }
+//	}()
+func (p *PromObserver) ObserveReconnect() {
+	p.reconnects.Inc()
+}
@@ -0,0 +1,139 @@
+package daas
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/lib/pq"
+)
+
+// PGStream iterates a query's result set one row at a time without
+// materializing it into a PGRecord, for exports and other large scans.
+type PGStream struct {
+	rows *sql.Rows
+	row  PGRow
+	err  error
+}
+
+// Stream runs query and returns a PGStream over its result set. Unlike
+// FetchAll, rows are decoded one at a time as Next is called.
+func (stx *PGTx) Stream(query string, args ...interface{}) (*PGStream, error) {
+	rows, err := sctxQuery(stx.tx, stx.ctx, stx.observer, false, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Stream::%s", err.Error())
+	}
+	return &PGStream{rows: rows}, nil
+}
+
+// Next advances to the next row, decoding it into Row. It returns false
+// at the end of the result set or once Err has been set.
+func (s *PGStream) Next() bool {
+	if s.err != nil || !s.rows.Next() {
+		return false
+	}
+	s.row, s.err = fetchRow(s.rows)
+	return s.err == nil
+}
+
+// Row returns the row most recently decoded by Next.
+func (s *PGStream) Row() PGRow {
+	return s.row
+}
+
+// Err returns the first error encountered while iterating, if any.
+func (s *PGStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.rows.Err()
+}
+
+// Close releases the underlying *sql.Rows. Safe to call after Next
+// returns false.
+func (s *PGStream) Close() error {
+	return s.rows.Close()
+}
+
+// CopyFormat selects the wire format used by CopyOut.
+type CopyFormat string
+
+const (
+	CopyFormatText   CopyFormat = "TEXT"
+	CopyFormatCSV    CopyFormat = "CSV"
+	CopyFormatBinary CopyFormat = "BINARY"
+)
+
+// RowSource feeds rows to CopyIn. Next advances to the next row,
+// returning false at the end or on error; Values returns that row's
+// column values in table/column order.
+type RowSource interface {
+	Next() bool
+	Values() []interface{}
+	Err() error
+}
+
+// CopyOut streams the result of query to w using PostgreSQL's
+// `COPY (query) TO STDOUT` protocol, avoiding both the parameter-count
+// limits and per-row overhead of paging through FetchAll. It returns
+// the number of bytes written.
+func (stx *PGTx) CopyOut(query string, w io.Writer, format CopyFormat) (int64, error) {
+	if format == "" {
+		format = CopyFormatText
+	}
+
+	copyQuery := fmt.Sprintf("COPY (%s) TO STDOUT WITH (FORMAT %s)", query, format)
+	rows, err := stx.tx.QueryContext(*stx.ctx, copyQuery)
+	if err != nil {
+		return 0, fmt.Errorf("CopyOut::Query: %s", err)
+	}
+	defer rows.Close()
+
+	var total int64
+	var chunk []byte
+	for rows.Next() {
+		if err := rows.Scan(&chunk); err != nil {
+			return total, fmt.Errorf("CopyOut::Scan: %s", err)
+		}
+		n, werr := w.Write(chunk)
+		total += int64(n)
+		if werr != nil {
+			return total, fmt.Errorf("CopyOut::Write: %s", werr)
+		}
+	}
+	return total, rows.Err()
+}
+
+// CopyIn bulk-loads every row produced by r into table's columns using
+// PostgreSQL's `COPY ... FROM STDIN` protocol via pq.CopyIn, which is
+// far cheaper than Execute with multi-row INSERT for high-throughput
+// loads. It returns the number of rows copied.
+func (stx *PGTx) CopyIn(table string, columns []string, r RowSource) (int64, error) {
+	if stx.ReadOnly {
+		return 0, fmt.Errorf("CopyIn:: transaction is read-only")
+	}
+
+	stmt, err := stx.tx.PrepareContext(*stx.ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return 0, fmt.Errorf("CopyIn::Prepare: %s", err)
+	}
+
+	var total int64
+	for r.Next() {
+		if _, err := stmt.ExecContext(*stx.ctx, r.Values()...); err != nil {
+			stmt.Close()
+			return total, fmt.Errorf("CopyIn::Exec: %s", err)
+		}
+		total++
+	}
+	if err := r.Err(); err != nil {
+		stmt.Close()
+		return total, fmt.Errorf("CopyIn::RowSource: %s", err)
+	}
+
+	if _, err := stmt.ExecContext(*stx.ctx); err != nil {
+		stmt.Close()
+		return total, fmt.Errorf("CopyIn::Flush: %s", err)
+	}
+	return total, stmt.Close()
+}